@@ -0,0 +1,258 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+)
+
+// Nagios-plugin-compatible exit codes.
+// See https://nagios-plugins.org/doc/guidelines.html#AEN78.
+const (
+	alertExitOK       = 0
+	alertExitWarning  = 1
+	alertExitCritical = 2
+	alertExitUnknown  = 3
+)
+
+var alertPredicateRe = regexp.MustCompile(`^([A-Za-z0-9_:.\-]+)(>=|<=|>|<)([0-9.]+)(?:!([0-9.]+))?$`)
+
+// AlertPredicate is one parsed clause of --alert, e.g. "cpu>80!90" meaning
+// field "cpu", operator ">", warning threshold 80, critical threshold 90.
+// A predicate with no "!value" (e.g. "cpu>90") has only a critical
+// threshold.
+type AlertPredicate struct {
+	Raw     string
+	Field   string
+	Op      string
+	Warn    float64
+	Crit    float64
+	HasCrit bool
+}
+
+// parseAlertSpec parses the comma-separated predicate list passed to
+// --alert, e.g. "cpu>90,load1>8,mem_used_pc>85,swap_used>0,proc:mysqld.cpu>50".
+func parseAlertSpec(spec string) ([]AlertPredicate, error) {
+	var predicates []AlertPredicate
+	for _, clause := range strings.Split(spec, ",") {
+		clause = strings.TrimSpace(clause)
+		if clause == "" {
+			continue
+		}
+
+		matches := alertPredicateRe.FindStringSubmatch(clause)
+		if matches == nil {
+			return nil, fmt.Errorf("invalid --alert predicate %q", clause)
+		}
+
+		warn, err := strconv.ParseFloat(matches[3], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid threshold in %q: %w", clause, err)
+		}
+
+		predicate := AlertPredicate{Raw: clause, Field: matches[1], Op: matches[2], Warn: warn}
+		if matches[4] != "" {
+			crit, err := strconv.ParseFloat(matches[4], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid threshold in %q: %w", clause, err)
+			}
+			predicate.Crit = crit
+			predicate.HasCrit = true
+		}
+		predicates = append(predicates, predicate)
+	}
+
+	if len(predicates) == 0 {
+		return nil, fmt.Errorf("--alert requires at least one predicate")
+	}
+	return predicates, nil
+}
+
+func compareThreshold(value float64, op string, threshold float64) bool {
+	switch op {
+	case ">":
+		return value > threshold
+	case ">=":
+		return value >= threshold
+	case "<":
+		return value < threshold
+	case "<=":
+		return value <= threshold
+	default:
+		return false
+	}
+}
+
+// alertMetrics builds the field => value lookup a predicate's Field is
+// evaluated against: the same fields the CLI/exporter already expose, plus
+// proc:<name>.cpu / proc:<name>.mem for the worst (highest-usage) process
+// matching that name, since more than one process can share a name.
+func alertMetrics(stats SystemStats, processes []Process) map[string]float64 {
+	metrics := map[string]float64{
+		"cpu":        stats.CPUUser + stats.CPUSystem,
+		"cpu_user":   stats.CPUUser,
+		"cpu_system": stats.CPUSystem,
+		"cpu_idle":   stats.CPUIdle,
+		"load1":      stats.LoadAverage1,
+		"load5":      stats.LoadAverage5,
+		"load15":     stats.LoadAverage15,
+		"swap_used":  stats.SwapTotalKB - stats.SwapFreeKB,
+	}
+	if stats.MemoryTotalKB > 0 {
+		metrics["mem_used_pc"] = (stats.MemoryTotalKB - stats.MemoryFreeKB) / stats.MemoryTotalKB * 100
+	}
+
+	for _, p := range processes {
+		cpuKey := "proc:" + p.Name + ".cpu"
+		if existing, ok := metrics[cpuKey]; !ok || p.CPUUsedPc > existing {
+			metrics[cpuKey] = p.CPUUsedPc
+		}
+		memKey := "proc:" + p.Name + ".mem"
+		if existing, ok := metrics[memKey]; !ok || p.MemoryUsedPc > existing {
+			metrics[memKey] = p.MemoryUsedPc
+		}
+	}
+
+	return metrics
+}
+
+// AlertFinding is the result of evaluating one predicate against a sample.
+type AlertFinding struct {
+	Predicate string  `json:"predicate"`
+	Value     float64 `json:"value,omitempty"`
+	Known     bool    `json:"known"`
+	Severity  string  `json:"severity"`
+}
+
+// evaluateAlerts evaluates every predicate against metrics and returns the
+// overall Nagios exit code (worst predicate wins) plus the per-predicate
+// findings.
+func evaluateAlerts(predicates []AlertPredicate, metrics map[string]float64) (int, []AlertFinding) {
+	exitCode := alertExitOK
+
+	findings := make([]AlertFinding, 0, len(predicates))
+	for _, p := range predicates {
+		value, ok := metrics[p.Field]
+		if !ok {
+			findings = append(findings, AlertFinding{Predicate: p.Raw, Known: false, Severity: "UNKNOWN"})
+			if exitCode < alertExitUnknown {
+				exitCode = alertExitUnknown
+			}
+			continue
+		}
+
+		severity := "OK"
+		switch {
+		case p.HasCrit && compareThreshold(value, p.Op, p.Crit):
+			severity = "CRITICAL"
+		case !p.HasCrit && compareThreshold(value, p.Op, p.Warn):
+			severity = "CRITICAL"
+		case p.HasCrit && compareThreshold(value, p.Op, p.Warn):
+			severity = "WARNING"
+		}
+
+		findings = append(findings, AlertFinding{Predicate: p.Raw, Value: value, Known: true, Severity: severity})
+
+		switch severity {
+		case "CRITICAL":
+			if exitCode < alertExitCritical {
+				exitCode = alertExitCritical
+			}
+		case "WARNING":
+			if exitCode < alertExitWarning {
+				exitCode = alertExitWarning
+			}
+		}
+	}
+
+	return exitCode, findings
+}
+
+// runAlert fetches the latest sample, evaluates options.alertSpec against
+// it, prints a one-line summary in options.alertFormat, and returns the
+// Nagios-compatible exit code (0 OK, 1 WARNING, 2 CRITICAL, 3 UNKNOWN) so
+// cron/monit/Nagios/Sensu can act on it.
+func runAlert(options RDSTopOptions, resourceID string, cloudWatchLogsSvc *cloudwatchlogs.CloudWatchLogs) int {
+	predicates, err := parseAlertSpec(options.alertSpec)
+	if err != nil {
+		fmt.Println("UNKNOWN:", err)
+		return alertExitUnknown
+	}
+
+	params := buildLogsParameters(resourceID, options.startTime)
+	messageJSON, err := getLogEvents(params, cloudWatchLogsSvc)
+	if err != nil {
+		fmt.Println("UNKNOWN: error getting log events:", err)
+		return alertExitUnknown
+	}
+
+	stats, err := parseSystemStats(messageJSON)
+	if err != nil {
+		fmt.Println("UNKNOWN: error parsing sample:", err)
+		return alertExitUnknown
+	}
+
+	metrics := alertMetrics(stats, parseProcessList(messageJSON))
+	exitCode, findings := evaluateAlerts(predicates, metrics)
+
+	if options.alertFormat == "json" {
+		printAlertJSON(exitCode, findings)
+	} else {
+		printAlertNagios(exitCode, findings)
+	}
+
+	return exitCode
+}
+
+func alertSeverityLabel(exitCode int) string {
+	switch exitCode {
+	case alertExitOK:
+		return "OK"
+	case alertExitWarning:
+		return "WARNING"
+	case alertExitCritical:
+		return "CRITICAL"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// printAlertNagios prints the classic Nagios-plugin one-liner: overall
+// severity followed by only the predicates that fired.
+func printAlertNagios(exitCode int, findings []AlertFinding) {
+	var fired []string
+	for _, f := range findings {
+		if f.Severity == "OK" {
+			continue
+		}
+		if !f.Known {
+			fired = append(fired, fmt.Sprintf("%s (unknown metric)", f.Predicate))
+			continue
+		}
+		fired = append(fired, fmt.Sprintf("%s (%.2f)", f.Predicate, f.Value))
+	}
+
+	if len(fired) == 0 {
+		fmt.Println("OK: all thresholds within range")
+		return
+	}
+	fmt.Printf("%s: %s\n", alertSeverityLabel(exitCode), strings.Join(fired, ", "))
+}
+
+func printAlertJSON(exitCode int, findings []AlertFinding) {
+	payload := struct {
+		Severity string         `json:"severity"`
+		Findings []AlertFinding `json:"findings"`
+	}{alertSeverityLabel(exitCode), findings}
+
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		fmt.Println("UNKNOWN: error encoding alert result:", err)
+		return
+	}
+	fmt.Println(string(encoded))
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// cachedSample is the subset of a sample that needs to survive between
+// invocations so rates can be computed against it: just enough to diff
+// against the next poll.
+type cachedSample struct {
+	Timestamp time.Time     `json:"timestamp"`
+	Network   []NetworkStat `json:"network"`
+}
+
+// rateCache tracks the previous sample per resourceID so network rx/tx can
+// be reported as per-second rates instead of raw cumulative counters. It
+// keeps the most recent sample in memory for the common case of --interval
+// looping within one process, and, unless persist is false, falls back to
+// reading and writing an on-disk cache under $XDG_CACHE_HOME/rds-top so a
+// single-shot invocation (e.g. from cron) can still compute a rate against
+// the previous run.
+//
+// persist must be false for --replay: replay walks historical samples, so
+// its "previous sample" is whatever came before it in the requested time
+// range, not the instance's actual last real-time reading. Persisting that
+// to disk would make the next plain invocation of rds-top diff against a
+// stale historical sample and silently print a bogus rate.
+type rateCache struct {
+	previous map[string]cachedSample
+	persist  bool
+}
+
+func newRateCache(persist bool) *rateCache {
+	return &rateCache{previous: make(map[string]cachedSample), persist: persist}
+}
+
+// previousSample returns the sample to diff resourceID's current reading
+// against, preferring the in-memory copy from an earlier iteration of this
+// same process over whatever a prior invocation left on disk.
+func (c *rateCache) previousSample(resourceID string) (cachedSample, bool) {
+	if s, ok := c.previous[resourceID]; ok {
+		return s, true
+	}
+	if !c.persist {
+		return cachedSample{}, false
+	}
+	s, err := loadCachedSample(resourceID)
+	if err != nil {
+		return cachedSample{}, false
+	}
+	return s, true
+}
+
+// record stores current as resourceID's sample for the next call to diff
+// against: in memory always, and on disk when c.persist is set.
+func (c *rateCache) record(resourceID string, current cachedSample) {
+	c.previous[resourceID] = current
+	if c.persist {
+		saveCachedSample(resourceID, current)
+	}
+}
+
+func cacheDir() (string, error) {
+	dir := os.Getenv("XDG_CACHE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".cache")
+	}
+	return filepath.Join(dir, "rds-top"), nil
+}
+
+func cacheFilePath(resourceID string) (string, error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, resourceID+".json"), nil
+}
+
+func loadCachedSample(resourceID string) (cachedSample, error) {
+	path, err := cacheFilePath(resourceID)
+	if err != nil {
+		return cachedSample{}, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cachedSample{}, err
+	}
+
+	var s cachedSample
+	if err := json.Unmarshal(data, &s); err != nil {
+		return cachedSample{}, err
+	}
+	return s, nil
+}
+
+// saveCachedSample best-effort persists s for resourceID; a cache write
+// failure (e.g. a read-only filesystem) shouldn't stop rds-top from
+// printing the current sample, so errors are swallowed.
+func saveCachedSample(resourceID string, s cachedSample) {
+	dir, err := cacheDir()
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return
+	}
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		return
+	}
+
+	path, err := cacheFilePath(resourceID)
+	if err != nil {
+		return
+	}
+	_ = os.WriteFile(path, data, 0o644)
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+// dashboardConcurrency bounds how many instances are resolved/polled at
+// once. The RDS and CloudWatch Logs clients are safe for concurrent use, so
+// this is just a worker-pool size sharing the one session.Session main()
+// already built.
+const dashboardConcurrency = 8
+
+// instanceRow is one line of the multi-instance dashboard.
+type instanceRow struct {
+	instanceID string
+	err        error
+	load1      float64
+	cpuUsedPc  float64
+	memUsedPc  float64
+	topProcess string
+}
+
+// getClusterMemberInstanceIDs expands an RDS cluster ID into the instance
+// identifiers of its current members, for --cluster.
+func getClusterMemberInstanceIDs(clusterID string, rdsSvc *rds.RDS) ([]string, error) {
+	result, err := rdsSvc.DescribeDBClusters(&rds.DescribeDBClustersInput{
+		DBClusterIdentifier: aws.String(clusterID),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(result.DBClusters) == 0 {
+		return nil, fmt.Errorf("no cluster found with ID %s", clusterID)
+	}
+
+	var instanceIDs []string
+	for _, member := range result.DBClusters[0].DBClusterMembers {
+		instanceIDs = append(instanceIDs, *member.DBInstanceIdentifier)
+	}
+	return instanceIDs, nil
+}
+
+// runDashboard prints a compact load/CPU/mem/top-process row per instance.
+// It refreshes every options.interval seconds when set, until
+// options.iterations passes are done (0 means forever), otherwise it prints
+// one pass and returns.
+func runDashboard(options RDSTopOptions, rdsSvc *rds.RDS, cloudWatchLogsSvc *cloudwatchlogs.CloudWatchLogs) {
+	resourceIDs, err := resolveResourceIDs(options.instanceIDs, rdsSvc)
+	if err != nil {
+		fmt.Println("Error resolving resource IDs:", err)
+		os.Exit(1)
+	}
+
+	for iteration := 1; ; iteration++ {
+		clearScreen()
+		printDashboard(options.instanceIDs, resourceIDs, options.startTime, options.aggregate, cloudWatchLogsSvc)
+
+		if options.interval <= 0 || (options.iterations > 0 && iteration >= options.iterations) {
+			return
+		}
+		time.Sleep(options.interval)
+	}
+}
+
+// resolveResourceIDs looks up the CloudWatch Logs resource ID for each
+// instance concurrently, bounded by dashboardConcurrency.
+func resolveResourceIDs(instanceIDs []string, rdsSvc *rds.RDS) (map[string]string, error) {
+	resourceIDs := make(map[string]string, len(instanceIDs))
+	var mu sync.Mutex
+	var firstErr error
+
+	sem := make(chan struct{}, dashboardConcurrency)
+	var wg sync.WaitGroup
+
+	for _, instanceID := range instanceIDs {
+		instanceID := instanceID
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			resourceID, err := getResourceID(instanceID, rdsSvc)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("%s: %w", instanceID, err)
+				}
+				return
+			}
+			resourceIDs[instanceID] = resourceID
+		}()
+	}
+
+	wg.Wait()
+	return resourceIDs, firstErr
+}
+
+// printDashboard fetches the latest sample for every instance concurrently,
+// bounded by dashboardConcurrency, and renders one row per instance plus,
+// when aggregate is set, trailing fleet-wide TOTAL and AVERAGE rows.
+func printDashboard(instanceIDs []string, resourceIDs map[string]string, startTime int64, aggregate bool, cloudWatchLogsSvc *cloudwatchlogs.CloudWatchLogs) {
+	rows := make([]instanceRow, len(instanceIDs))
+	sem := make(chan struct{}, dashboardConcurrency)
+	var wg sync.WaitGroup
+
+	for i, instanceID := range instanceIDs {
+		i, instanceID := i, instanceID
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			rows[i] = fetchInstanceRow(instanceID, resourceIDs[instanceID], startTime, cloudWatchLogsSvc)
+		}()
+	}
+	wg.Wait()
+
+	format := "%-24s %-8s %-8s %-8s %s\n"
+	fmt.Printf(format, "INSTANCE", "LOAD1", "%CPU", "%MEM", "TOP PROCESS")
+
+	var totalLoad1, totalCPU, totalMem float64
+	var ok int
+	for _, row := range rows {
+		if row.err != nil {
+			fmt.Printf("%-24s error: %v\n", row.instanceID, row.err)
+			continue
+		}
+		fmt.Printf(format, row.instanceID, fmt.Sprintf("%.2f", row.load1), fmt.Sprintf("%.2f", row.cpuUsedPc), fmt.Sprintf("%.2f", row.memUsedPc), row.topProcess)
+		totalLoad1 += row.load1
+		totalCPU += row.cpuUsedPc
+		totalMem += row.memUsedPc
+		ok++
+	}
+
+	if aggregate && ok > 0 {
+		fmt.Println()
+		fmt.Printf(format, "TOTAL", fmt.Sprintf("%.2f", totalLoad1), fmt.Sprintf("%.2f", totalCPU), fmt.Sprintf("%.2f", totalMem), "")
+		fmt.Printf(format, "AVERAGE", fmt.Sprintf("%.2f", totalLoad1/float64(ok)), fmt.Sprintf("%.2f", totalCPU/float64(ok)), fmt.Sprintf("%.2f", totalMem/float64(ok)), "")
+	}
+}
+
+// fetchInstanceRow fetches and parses the latest sample for one instance.
+func fetchInstanceRow(instanceID, resourceID string, startTime int64, cloudWatchLogsSvc *cloudwatchlogs.CloudWatchLogs) instanceRow {
+	if resourceID == "" {
+		return instanceRow{instanceID: instanceID, err: fmt.Errorf("no resource ID resolved")}
+	}
+
+	params := buildLogsParameters(resourceID, startTime)
+	messageJSON, err := getLogEvents(params, cloudWatchLogsSvc)
+	if err != nil {
+		return instanceRow{instanceID: instanceID, err: err}
+	}
+
+	stats, err := parseSystemStats(messageJSON)
+	if err != nil {
+		return instanceRow{instanceID: instanceID, err: err}
+	}
+
+	memUsedPc := 0.0
+	if stats.MemoryTotalKB > 0 {
+		memUsedPc = (stats.MemoryTotalKB - stats.MemoryFreeKB) / stats.MemoryTotalKB * 100
+	}
+
+	processes := parseProcessList(messageJSON)
+	sort.Slice(processes, func(i, j int) bool {
+		return processes[i].CPUUsedPc > processes[j].CPUUsedPc
+	})
+	topProcess := ""
+	if len(processes) > 0 {
+		topProcess = processes[0].Name
+	}
+
+	return instanceRow{
+		instanceID: instanceID,
+		load1:      stats.LoadAverage1,
+		cpuUsedPc:  stats.CPUUser + stats.CPUSystem,
+		memUsedPc:  memUsedPc,
+		topProcess: topProcess,
+	}
+}
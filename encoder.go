@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// sample bundles one messageJSON's parsed panels, with the process list
+// already sorted and filtered and network rates already computed, so every
+// Encoder can share the same parsing instead of reimplementing it.
+type sample struct {
+	System       SystemStats
+	Network      []NetworkStat
+	NetworkRates []NetworkRate
+	Disk         []DiskIOStat
+	Processes    []Process
+}
+
+func parseSample(messageJSON string, sortByMem bool, processFilter string, resourceID string, cache *rateCache) (sample, error) {
+	stats, err := parseSystemStats(messageJSON)
+	if err != nil {
+		return sample{}, err
+	}
+
+	var processes []Process
+	for _, p := range parseProcessList(messageJSON) {
+		if processFilter != "" && !strings.Contains(p.Name, processFilter) {
+			continue
+		}
+		processes = append(processes, p)
+	}
+	sort.Slice(processes, func(i, j int) bool {
+		if sortByMem {
+			return processes[i].MemoryUsedPc > processes[j].MemoryUsedPc
+		}
+		return processes[i].CPUUsedPc > processes[j].CPUUsedPc
+	})
+
+	network := parseNetworkStats(messageJSON)
+
+	var rates []NetworkRate
+	if previous, ok := cache.previousSample(resourceID); ok {
+		rates = computeNetworkRates(previous, stats.Timestamp, network)
+	}
+	cache.record(resourceID, cachedSample{Timestamp: stats.Timestamp, Network: network})
+
+	return sample{
+		System:       stats,
+		Network:      network,
+		NetworkRates: rates,
+		Disk:         parseDiskIOStats(messageJSON),
+		Processes:    processes,
+	}, nil
+}
+
+// newEncoder returns the Renderer for --output. "table" is the original
+// ConsoleRenderer; the others serialize the same parsed fields for piping
+// into jq, telegraf's exec input, or awk. All of them share one rateCache so
+// network rx/tx come out as rates rather than raw cumulative counters.
+// persistCache must be false for --replay; see rateCache's doc comment.
+func newEncoder(format string, persistCache bool) (Renderer, error) {
+	cache := newRateCache(persistCache)
+
+	switch format {
+	case "", "table":
+		return &ConsoleRenderer{cache: cache}, nil
+	case "json":
+		return &JSONEncoder{cache: cache}, nil
+	case "csv":
+		return &CSVEncoder{cache: cache}, nil
+	case "influx":
+		return &InfluxEncoder{cache: cache}, nil
+	default:
+		return nil, fmt.Errorf("unknown --output format %q", format)
+	}
+}
+
+// JSONEncoder renders a sample as a single JSON object per line.
+type JSONEncoder struct {
+	cache *rateCache
+}
+
+func (e *JSONEncoder) Render(messageJSON string, sortByMem bool, processFilter string, resourceID string) {
+	s, err := parseSample(messageJSON, sortByMem, processFilter, resourceID, e.cache)
+	if err != nil {
+		fmt.Println("Error parsing sample:", err)
+		return
+	}
+
+	payload := struct {
+		System       SystemStats   `json:"system"`
+		Network      []NetworkStat `json:"network"`
+		NetworkRates []NetworkRate `json:"networkRates"`
+		Disk         []DiskIOStat  `json:"disk"`
+		Processes    []Process     `json:"processes"`
+	}{s.System, s.Network, s.NetworkRates, s.Disk, s.Processes}
+
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		fmt.Println("Error encoding JSON:", err)
+		return
+	}
+	fmt.Println(string(encoded))
+}
+
+// CSVEncoder renders a sample as long-format CSV rows: timestamp, type,
+// label, field, value. The system/network/disk/process panels don't share
+// columns, so rather than forcing one wide table, each metric gets its own
+// row - easy to grep/awk a single field across every instance. Rows are
+// written with encoding/csv so a label containing a comma, quote, or
+// newline (a process name, say) is quoted per RFC 4180 instead of shifting
+// columns.
+type CSVEncoder struct {
+	cache *rateCache
+}
+
+func (e *CSVEncoder) Render(messageJSON string, sortByMem bool, processFilter string, resourceID string) {
+	s, err := parseSample(messageJSON, sortByMem, processFilter, resourceID, e.cache)
+	if err != nil {
+		fmt.Println("Error parsing sample:", err)
+		return
+	}
+
+	w := csv.NewWriter(os.Stdout)
+	defer w.Flush()
+
+	ts := s.System.Timestamp.Format(time.RFC3339)
+	row := func(typ, label, field string, value interface{}) {
+		w.Write([]string{ts, typ, label, field, fmt.Sprintf("%v", value)})
+	}
+
+	row("system", s.System.InstanceID, "load1", s.System.LoadAverage1)
+	row("system", s.System.InstanceID, "load5", s.System.LoadAverage5)
+	row("system", s.System.InstanceID, "load15", s.System.LoadAverage15)
+	row("system", s.System.InstanceID, "cpu_user_pc", s.System.CPUUser)
+	row("system", s.System.InstanceID, "cpu_system_pc", s.System.CPUSystem)
+	row("system", s.System.InstanceID, "cpu_idle_pc", s.System.CPUIdle)
+	row("system", s.System.InstanceID, "mem_total_kb", s.System.MemoryTotalKB)
+	row("system", s.System.InstanceID, "mem_free_kb", s.System.MemoryFreeKB)
+	row("system", s.System.InstanceID, "swap_total_kb", s.System.SwapTotalKB)
+	row("system", s.System.InstanceID, "swap_free_kb", s.System.SwapFreeKB)
+
+	for _, r := range s.NetworkRates {
+		row("network", r.Interface, "rx_bytes_per_sec", r.RxBytesPerSec)
+		row("network", r.Interface, "tx_bytes_per_sec", r.TxBytesPerSec)
+	}
+
+	for _, d := range s.Disk {
+		row("disk", d.Device, "tps", d.TPS)
+		row("disk", d.Device, "await_ms", d.Await)
+		row("disk", d.Device, "util_pc", d.UtilPc)
+	}
+
+	for _, p := range s.Processes {
+		label := fmt.Sprintf("%d:%s", p.ID, p.Name)
+		row("process", label, "cpu_pc", p.CPUUsedPc)
+		row("process", label, "mem_pc", p.MemoryUsedPc)
+		row("process", label, "rss_kb", p.RSS)
+	}
+}
+
+// InfluxEncoder renders a sample as InfluxDB line protocol, one line per
+// measurement, suitable for a Telegraf exec input.
+type InfluxEncoder struct {
+	cache *rateCache
+}
+
+func (e *InfluxEncoder) Render(messageJSON string, sortByMem bool, processFilter string, resourceID string) {
+	s, err := parseSample(messageJSON, sortByMem, processFilter, resourceID, e.cache)
+	if err != nil {
+		fmt.Println("Error parsing sample:", err)
+		return
+	}
+
+	ts := s.System.Timestamp.UnixNano()
+	instance := influxTagValue(s.System.InstanceID)
+
+	fmt.Printf("rds_system,instance=%s load1=%g,load5=%g,load15=%g,cpu_user=%g,cpu_system=%g,cpu_idle=%g,mem_total_kb=%g,mem_free_kb=%g,swap_total_kb=%g,swap_free_kb=%g %d\n",
+		instance, s.System.LoadAverage1, s.System.LoadAverage5, s.System.LoadAverage15, s.System.CPUUser, s.System.CPUSystem, s.System.CPUIdle, s.System.MemoryTotalKB, s.System.MemoryFreeKB, s.System.SwapTotalKB, s.System.SwapFreeKB, ts)
+
+	for _, r := range s.NetworkRates {
+		fmt.Printf("rds_network,instance=%s,interface=%s rx_bytes_per_sec=%g,tx_bytes_per_sec=%g %d\n", instance, influxTagValue(r.Interface), r.RxBytesPerSec, r.TxBytesPerSec, ts)
+	}
+
+	for _, d := range s.Disk {
+		fmt.Printf("rds_disk,instance=%s,device=%s tps=%g,await_ms=%g,util_pc=%g %d\n", instance, influxTagValue(d.Device), d.TPS, d.Await, d.UtilPc, ts)
+	}
+
+	for _, p := range s.Processes {
+		fmt.Printf("rds_process,instance=%s,pid=%d,name=%s cpu_pc=%g,mem_pc=%g,rss_kb=%di %d\n", instance, p.ID, influxTagValue(p.Name), p.CPUUsedPc, p.MemoryUsedPc, p.RSS, ts)
+	}
+}
+
+// influxTagValue escapes commas, spaces and equals signs in a tag value per
+// the Influx line protocol spec. Without this, a process name like
+// "postgres: main process" (a real RDS Postgres process title) would put an
+// unescaped space into the tag set, which is indistinguishable from the
+// tag-set/field-set separator and breaks the line for any parser.
+func influxTagValue(v string) string {
+	r := strings.NewReplacer(",", `\,`, " ", `\ `, "=", `\=`)
+	return r.Replace(v)
+}
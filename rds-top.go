@@ -8,10 +8,11 @@
 //	./rds-top rds-instance
 //	./rds-top --start-time=$(date -v-13d +%s) rds-instance
 //	./rds-top --sort-by-mem --start-time=$(date -j -f "%Y-%m-%dT%H:%M:%S%z" "2019-09-12T13:05:00+0000" +%s) rds-instance | grep -v 'idle$'
+//	./rds-top --interval=5 rds-instance
 package main
 
 import (
-	"encoding/json"
+	"bufio"
 	"errors"
 	"flag"
 	"fmt"
@@ -21,20 +22,40 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
 	"github.com/aws/aws-sdk-go/service/rds"
-	"github.com/tidwall/gjson"
 )
 
 // RDSTopOptions contains command-line options for the rds-top tool.
 type RDSTopOptions struct {
-	startTime  int64
-	sortByMem  bool
-	instanceID string
+	startTime     int64
+	endTime       int64
+	sortByMem     bool
+	instanceID    string
+	instanceIDs   []string
+	cluster       string
+	aggregate     bool
+	interval      time.Duration
+	iterations    int
+	replay        bool
+	playbackSpeed float64
+	output        string
+	alertSpec     string
+	alertFormat   string
+}
+
+// liveState holds the parts of the render loop that the keyboard listener
+// is allowed to mutate while a refresh is in flight.
+type liveState struct {
+	mu            sync.Mutex
+	sortByMem     bool
+	processFilter string
+	quit          bool
 }
 
 // Process represents a single process running on an RDS instance.
@@ -49,6 +70,14 @@ type Process struct {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		if err := runServe(os.Args[2:]); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	options, err := parseFlags()
 	if err != nil {
 		fmt.Println(err)
@@ -65,12 +94,60 @@ func main() {
 	rdsSvc := rds.New(sess)
 	cloudWatchLogsSvc := cloudwatchlogs.New(sess)
 
+	if options.cluster != "" {
+		memberIDs, err := getClusterMemberInstanceIDs(options.cluster, rdsSvc)
+		if err != nil {
+			fmt.Println("Error expanding cluster:", err)
+			os.Exit(1)
+		}
+		options.instanceIDs = memberIDs
+		if len(options.instanceIDs) == 1 {
+			options.instanceID = options.instanceIDs[0]
+		}
+	}
+
+	if len(options.instanceIDs) > 1 {
+		if options.alertSpec != "" {
+			fmt.Println("Error: --alert is not supported together with multiple instances or --cluster; run it against a single instance")
+			os.Exit(1)
+		}
+		if options.output != "" && options.output != "table" {
+			fmt.Println("Error: --output is not supported together with multiple instances or --cluster; run it against a single instance")
+			os.Exit(1)
+		}
+		runDashboard(options, rdsSvc, cloudWatchLogsSvc)
+		return
+	}
+
 	resourceID, err := getResourceID(options.instanceID, rdsSvc)
 	if err != nil {
 		fmt.Println("Error getting resource ID:", err)
 		os.Exit(1)
 	}
 
+	if options.alertSpec != "" {
+		os.Exit(runAlert(options, resourceID, cloudWatchLogsSvc))
+	}
+
+	renderer, err := newEncoder(options.output, !options.replay)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	if options.replay {
+		if err := runReplay(options, resourceID, cloudWatchLogsSvc, renderer); err != nil {
+			fmt.Println("Error replaying log events:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if options.interval > 0 {
+		runLiveLoop(options, resourceID, cloudWatchLogsSvc, renderer)
+		return
+	}
+
 	params := buildLogsParameters(resourceID, options.startTime)
 
 	messageJSON, err := getLogEvents(params, cloudWatchLogsSvc)
@@ -79,15 +156,200 @@ func main() {
 		os.Exit(1)
 	}
 
+	renderer.Render(messageJSON, options.sortByMem, "", resourceID)
+}
+
+// Renderer draws a single parsed RDSOSMetrics sample. It is the seam
+// between fetching/looping (main, runLiveLoop) and presentation, so the
+// same sample can be pushed through a plain console dump or, eventually,
+// other presentations such as an exporter. resourceID identifies the
+// CloudWatch Logs stream the sample came from, so a Renderer that needs the
+// previous sample (to compute a rate) knows which cache entry to use.
+type Renderer interface {
+	Render(messageJSON string, sortByMem bool, processFilter string, resourceID string)
+}
+
+// ConsoleRenderer renders a sample the way the original one-shot rds-top
+// did: system/network/disk panels followed by the process list. Network
+// rx/tx are rendered as rates using cache's previous sample for the
+// instance being rendered.
+type ConsoleRenderer struct {
+	cache *rateCache
+}
+
+// Render prints system, network, disk and process panels for messageJSON.
+// processFilter, when non-empty, restricts the process list to names
+// containing the given substring.
+func (r *ConsoleRenderer) Render(messageJSON string, sortByMem bool, processFilter string, resourceID string) {
 	printSystemStats(messageJSON)
 	fmt.Println()
 
-	printNetworkStats(messageJSON)
+	printNetworkStats(messageJSON, resourceID, r.cache)
 	printDiskIOStats(messageJSON)
 
 	fmt.Println()
-	printProcessList(messageJSON, options.sortByMem)
+	printProcessList(messageJSON, sortByMem, processFilter)
+}
+
+// runReplay walks every sample between options.startTime and options.endTime
+// in timestamp order, rendering each one in place like runLiveLoop does,
+// pacing itself to the real gap between consecutive samples divided by
+// options.playbackSpeed so forensic playback feels like watching `top`
+// batch output rather than a dump.
+func runReplay(options RDSTopOptions, resourceID string, cloudWatchLogsSvc *cloudwatchlogs.CloudWatchLogs, renderer Renderer) error {
+	params := buildReplayLogsParameters(resourceID, options.startTime, options.endTime)
+	samples, err := getLogEventsRange(params, cloudWatchLogsSvc)
+	if err != nil {
+		return err
+	}
+
+	var previousTimestamp time.Time
+	for _, messageJSON := range samples {
+		stats, err := parseSystemStats(messageJSON)
+		if err == nil && !previousTimestamp.IsZero() {
+			if gap := stats.Timestamp.Sub(previousTimestamp); gap > 0 {
+				time.Sleep(time.Duration(float64(gap) / options.playbackSpeed))
+			}
+		}
+		if err == nil {
+			previousTimestamp = stats.Timestamp
+		}
+
+		clearScreen()
+		renderer.Render(messageJSON, options.sortByMem, "", resourceID)
+	}
+
+	return nil
+}
+
+// runLiveLoop repeatedly fetches the latest sample and re-renders it in
+// place, top-style, until the user quits or options.iterations is reached.
+// Keyboard controls are handled concurrently via a cbreak-mode stdin reader:
+// q quits, m toggles sort-by-mem, P sorts by CPU, k starts reading a process
+// filter (typed without echo or an on-screen prompt; Enter applies it).
+func runLiveLoop(options RDSTopOptions, resourceID string, cloudWatchLogsSvc *cloudwatchlogs.CloudWatchLogs, renderer Renderer) {
+	state := &liveState{sortByMem: options.sortByMem}
+
+	restore, err := enableKeypressMode(state)
+	if err != nil {
+		fmt.Println("Warning: keyboard controls disabled:", err)
+	}
+	if restore != nil {
+		defer restore()
+	}
+
+	for iteration := 0; options.iterations <= 0 || iteration < options.iterations; iteration++ {
+		state.mu.Lock()
+		if state.quit {
+			state.mu.Unlock()
+			return
+		}
+		sortByMem := state.sortByMem
+		processFilter := state.processFilter
+		state.mu.Unlock()
+
+		params := buildLogsParameters(resourceID, options.startTime)
+		messageJSON, err := getLogEvents(params, cloudWatchLogsSvc)
+		if err != nil {
+			fmt.Println("Error getting log events:", err)
+			os.Exit(1)
+		}
+
+		clearScreen()
+		renderer.Render(messageJSON, sortByMem, processFilter, resourceID)
+
+		time.Sleep(options.interval)
+	}
+}
+
+// enableKeypressMode puts the controlling terminal into cbreak mode via
+// stty - the same way clearScreen shells out to "clear"/"cls" instead of
+// taking over the terminal itself - and starts a goroutine reading key
+// presses in the background. cbreak mode only disables line buffering and
+// local echo (so single keystrokes are delivered immediately); unlike a
+// full raw mode it leaves output processing alone, so the terminal still
+// turns each "\n" from the existing Println-based rendering into a proper
+// CRLF instead of leaving the cursor stuck in the same column. It returns a
+// restore func that puts the terminal back how it found it, and a non-nil
+// error on Windows, which has no stty and no controlling tty in the same
+// sense.
+func enableKeypressMode(state *liveState) (func(), error) {
+	if runtime.GOOS == "windows" {
+		return nil, errors.New("keyboard controls are not supported on Windows")
+	}
+
+	saved, err := exec.Command("stty", "-g").Output()
+	if err != nil {
+		return nil, fmt.Errorf("reading terminal settings: %w", err)
+	}
+
+	cbreak := exec.Command("stty", "cbreak", "-echo")
+	cbreak.Stdin = os.Stdin
+	if err := cbreak.Run(); err != nil {
+		return nil, fmt.Errorf("entering cbreak mode: %w", err)
+	}
 
+	go readKeys(state)
+
+	return func() {
+		r := exec.Command("stty", strings.TrimSpace(string(saved)))
+		r.Stdin = os.Stdin
+		r.Run()
+	}, nil
+}
+
+// readKeys reads key presses from stdin one rune at a time and mutates
+// state accordingly. It runs for the lifetime of the live loop, reading
+// whatever enableKeypressMode left stdin configured as.
+func readKeys(state *liveState) {
+	reader := bufio.NewReader(os.Stdin)
+	filtering := false
+	var filterBuf []rune
+
+	for {
+		r, _, err := reader.ReadRune()
+		if err != nil {
+			return
+		}
+
+		if filtering {
+			switch r {
+			case '\r', '\n':
+				state.mu.Lock()
+				state.processFilter = string(filterBuf)
+				state.mu.Unlock()
+				filtering = false
+			case 27: // Esc
+				filtering = false
+			case 127, 8: // Backspace/DEL
+				if len(filterBuf) > 0 {
+					filterBuf = filterBuf[:len(filterBuf)-1]
+				}
+			default:
+				filterBuf = append(filterBuf, r)
+			}
+			continue
+		}
+
+		switch r {
+		case 'q', 'Q':
+			state.mu.Lock()
+			state.quit = true
+			state.mu.Unlock()
+			return
+		case 'm':
+			state.mu.Lock()
+			state.sortByMem = !state.sortByMem
+			state.mu.Unlock()
+		case 'P':
+			state.mu.Lock()
+			state.sortByMem = false
+			state.mu.Unlock()
+		case 'k':
+			filtering = true
+			filterBuf = filterBuf[:0]
+		}
+	}
 }
 
 func clearScreen() {
@@ -106,30 +368,107 @@ func clearScreen() {
 
 func parseFlags() (RDSTopOptions, error) {
 	startTimeFlag := flag.String("start-time", "", "Optional: Specify the start time in seconds since the Unix epoch")
+	endTimeFlag := flag.String("end-time", "", "Optional: Specify the end time in seconds since the Unix epoch. Used with --replay")
 	sortByMemFlag := flag.Bool("sort-by-mem", false, "Optional: Sorts output by memory. Default is to sort by CPU")
+	intervalFlag := flag.Int("interval", 0, "Optional: Refresh interval in seconds. When set, rds-top runs continuously like top instead of printing once")
+	iterationsFlag := flag.Int("iterations", 0, "Optional: Number of refreshes to perform in --interval mode before exiting. Default 0 means run until quit")
+	replayFlag := flag.Bool("replay", false, "Optional: Walk through every sample between --start-time and --end-time instead of printing a single one")
+	playbackSpeedFlag := flag.String("playback-speed", "1x", "Optional: Playback speed for --replay, e.g. 2x, 0.5x")
+	clusterFlag := flag.String("cluster", "", "Optional: RDS cluster ID; expands to its member instances for the dashboard view")
+	aggregateFlag := flag.Bool("aggregate", false, "Optional: In a multi-instance dashboard, also print fleet-wide sum and average rows")
+	outputFlag := flag.String("output", "table", "Optional: Output format: table, json, csv, or influx")
+	alertFlag := flag.String("alert", "", "Optional: Comma-separated threshold predicates, e.g. 'cpu>90,load1>8,proc:mysqld.cpu>80!95'. Exits with a Nagios-compatible code instead of printing panels")
+	alertFormatFlag := flag.String("alert-format", "nagios", "Optional: --alert summary format: nagios or json")
 
 	flag.Parse()
 
-	if flag.NArg() != 1 {
+	if *clusterFlag == "" && flag.NArg() < 1 {
 		return RDSTopOptions{}, errors.New("invalid number of arguments")
 	}
-	instanceID := flag.Arg(0)
+	instanceIDs := flag.Args()
+	instanceID := ""
+	if len(instanceIDs) > 0 {
+		instanceID = instanceIDs[0]
+	}
+
+	playbackSpeed, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSuffix(*playbackSpeedFlag, "x"), "X"), 64)
+	if err != nil || playbackSpeed <= 0 {
+		return RDSTopOptions{}, errors.New("invalid playback speed format")
+	}
+
+	if *alertFormatFlag != "nagios" && *alertFormatFlag != "json" {
+		return RDSTopOptions{}, errors.New("invalid --alert-format, must be nagios or json")
+	}
+
+	options := RDSTopOptions{
+		sortByMem:     *sortByMemFlag,
+		instanceID:    instanceID,
+		instanceIDs:   instanceIDs,
+		cluster:       *clusterFlag,
+		aggregate:     *aggregateFlag,
+		interval:      time.Duration(*intervalFlag) * time.Second,
+		iterations:    *iterationsFlag,
+		replay:        *replayFlag,
+		playbackSpeed: playbackSpeed,
+		output:        *outputFlag,
+		alertSpec:     *alertFlag,
+		alertFormat:   *alertFormatFlag,
+	}
+
 	if *startTimeFlag != "" {
 		startTime, err := strconv.ParseInt(*startTimeFlag, 10, 64)
 		if err != nil {
 			return RDSTopOptions{}, errors.New("invalid start time format")
 		}
-		return RDSTopOptions{startTime: startTime, sortByMem: *sortByMemFlag, instanceID: instanceID}, nil
+		options.startTime = startTime
+	}
+
+	if *endTimeFlag != "" {
+		endTime, err := strconv.ParseInt(*endTimeFlag, 10, 64)
+		if err != nil {
+			return RDSTopOptions{}, errors.New("invalid end time format")
+		}
+		options.endTime = endTime
+	}
+
+	if options.replay && options.startTime == 0 {
+		return RDSTopOptions{}, errors.New("--replay requires --start-time")
 	}
 
-	return RDSTopOptions{startTime: 0, sortByMem: *sortByMemFlag, instanceID: instanceID}, nil
+	return options, nil
 }
 
 func usage() {
-	fmt.Println(`Usage: rds-top [options] rds_instance_id
+	fmt.Println(`Usage: rds-top [options] rds_instance_id [rds_instance_id ...]
+       rds-top [options] --cluster rds_cluster_id
+       rds-top serve [options]     Run a Prometheus exporter instead; see 'rds-top serve --help'
+
+Passing more than one instance ID (or a --cluster with more than one
+member) switches to a compact multi-instance dashboard view instead of
+the single-instance panels.
+--alert and --output are single-instance features and are rejected with
+an error in that mode rather than silently ignored.
+
 OPTIONS:
 	--start-time=t           Optional: Specify the start time in seconds since the Unix epoch
-	--sort-by-mem            Optional: Sorts output by memory. Default is to sort by CPU`)
+	--end-time=t             Optional: Specify the end time in seconds since the Unix epoch. Used with --replay
+	--cluster=id             Optional: Expand an RDS cluster ID into its member instances for the dashboard view
+	--aggregate              Optional: In the multi-instance dashboard, also print fleet-wide sum and average rows
+	--output=fmt             Optional: Output format: table (default), json, csv, or influx
+	--alert=spec             Optional: Comma-separated threshold predicates, e.g. 'cpu>90,load1>8,proc:mysqld.cpu>80!95'
+	                         Exits 0/1/2/3 (Nagios OK/WARNING/CRITICAL/UNKNOWN) instead of printing panels
+	--alert-format=fmt       Optional: --alert summary format: nagios (default) or json
+	--sort-by-mem            Optional: Sorts output by memory. Default is to sort by CPU
+	--interval=N             Optional: Refresh every N seconds like top, instead of printing once
+	--iterations=N           Optional: Number of refreshes to perform in --interval mode before exiting
+	--replay                 Optional: Walk through every sample between --start-time and --end-time
+	--playback-speed=Nx      Optional: Playback speed for --replay, e.g. 2x, 0.5x (default 1x)
+
+KEYS (in single-instance --interval mode; the dashboard view reads no keys):
+	q                        Quit
+	m                        Toggle sorting by memory / CPU
+	P                        Sort by CPU
+	k                        Filter process list by name (Enter to apply, Esc to cancel)`)
 }
 
 func getResourceID(instanceID string, rdsSvc *rds.RDS) (string, error) {
@@ -160,6 +499,23 @@ func buildLogsParameters(resourceID string, startTime int64) *cloudwatchlogs.Get
 	return params
 }
 
+// buildReplayLogsParameters builds the GetLogEvents request used for
+// --replay: unlike buildLogsParameters it has no Limit, so a full page of
+// samples is retrieved per call, and it carries an EndTime so playback stops
+// at the end of the requested window.
+func buildReplayLogsParameters(resourceID string, startTime, endTime int64) *cloudwatchlogs.GetLogEventsInput {
+	params := &cloudwatchlogs.GetLogEventsInput{
+		LogGroupName:  aws.String("RDSOSMetrics"),
+		LogStreamName: aws.String(resourceID),
+		StartTime:     aws.Int64(startTime * 1000),
+		StartFromHead: aws.Bool(true),
+	}
+	if endTime > 0 {
+		params.EndTime = aws.Int64(endTime * 1000)
+	}
+	return params
+}
+
 func getLogEvents(params *cloudwatchlogs.GetLogEventsInput, cloudWatchLogsSvc *cloudwatchlogs.CloudWatchLogs) (string, error) {
 	result, err := cloudWatchLogsSvc.GetLogEvents(params)
 	if err != nil {
@@ -173,47 +529,105 @@ func getLogEvents(params *cloudwatchlogs.GetLogEventsInput, cloudWatchLogsSvc *c
 	return strings.Join(logMessages, ""), nil
 }
 
+// getLogEventsRange retrieves every sample in params' [StartTime, EndTime)
+// window, oldest first, paginating via NextForwardToken until CloudWatch
+// Logs stops returning new events (it signals this by echoing back the same
+// token we sent it).
+func getLogEventsRange(params *cloudwatchlogs.GetLogEventsInput, cloudWatchLogsSvc *cloudwatchlogs.CloudWatchLogs) ([]string, error) {
+	var samples []string
+	var token *string
+
+	for {
+		params.NextToken = token
+
+		result, err := cloudWatchLogsSvc.GetLogEvents(params)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, event := range result.Events {
+			samples = append(samples, *event.Message)
+		}
+
+		if len(result.Events) == 0 || result.NextForwardToken == nil {
+			break
+		}
+		if token != nil && *result.NextForwardToken == *token {
+			break
+		}
+		token = result.NextForwardToken
+	}
+
+	return samples, nil
+}
+
 func printSystemStats(messageJSON string) {
-	systemStats := gjson.GetMany(messageJSON, "instanceID", "timestamp", "uptime", "loadAverageMinute.one", "loadAverageMinute.five", "loadAverageMinute.fifteen", "tasks.total", "tasks.running", "tasks.sleeping", "tasks.stopped", "tasks.zombie", "cpuUtilization.user", "cpuUtilization.system", "cpuUtilization.nice", "cpuUtilization.idle", "cpuUtilization.wait", "cpuUtilization.steal", "memory.total", "memory.free", "memory.cached", "memory.buffers", "swap.total", "swap.free", "swap.cached")
+	stats, err := parseSystemStats(messageJSON)
+	if err != nil {
+		fmt.Println("Error parsing system stats:", err)
+		return
+	}
+
+	fmt.Printf("%s - %s - %s up, load average: %.2f, %.2f, %.2f\n", stats.InstanceID, stats.Timestamp.Format(time.RFC3339), stats.Uptime, stats.LoadAverage1, stats.LoadAverage5, stats.LoadAverage15)
+	fmt.Printf("Tasks: %d total, %d running, %d sleeping, %d stopped, %d zombie\n", stats.TasksTotal, stats.TasksRunning, stats.TasksSleeping, stats.TasksStopped, stats.TasksZombie)
+	fmt.Printf("%%Cpu(s): %.2f us, %.2f sy, %.2f ni, %.2f id, %.2f wa, %.2f st\n", stats.CPUUser, stats.CPUSystem, stats.CPUNice, stats.CPUIdle, stats.CPUWait, stats.CPUSteal)
+	fmt.Printf("MiB Mem: %.2f total, %.2f free, %.2f used, %.2f buff/cache\n", stats.MemoryTotalKB/1024, stats.MemoryFreeKB/1024, (stats.MemoryTotalKB-stats.MemoryFreeKB)/1024, (stats.MemoryCachedKB+stats.MemoryBuffersKB)/1024)
+	fmt.Printf("MiB Swap: %.2f total, %.2f free, %.2f cached\n", stats.SwapTotalKB/1024, stats.SwapFreeKB/1024, stats.SwapCachedKB)
+}
 
-	timestampStr := systemStats[1].String()
-	timestamp, err := time.Parse(time.RFC3339, timestampStr)
+// printNetworkStats prints rx/tx for each interface as a human-readable
+// rate (KB/s or MB/s), diffing the current sample against cache's previous
+// sample for resourceID. The very first sample for a resourceID has nothing
+// to diff against, so it falls back to the raw counters.
+func printNetworkStats(messageJSON string, resourceID string, cache *rateCache) {
+	stats, err := parseSystemStats(messageJSON)
 	if err != nil {
-		fmt.Println("Error parsing timestamp:", err)
+		fmt.Println("Error parsing system stats:", err)
 		return
 	}
+	current := parseNetworkStats(messageJSON)
+
+	previous, havePrevious := cache.previousSample(resourceID)
+	cache.record(resourceID, cachedSample{Timestamp: stats.Timestamp, Network: current})
+
+	rateByInterface := make(map[string]NetworkRate)
+	if havePrevious {
+		for _, rate := range computeNetworkRates(previous, stats.Timestamp, current) {
+			rateByInterface[rate.Interface] = rate
+		}
+	}
 
-	fmt.Printf("%s - %s - %s up, load average: %.2f, %.2f, %.2f\n", systemStats[0].String(), timestamp.Format(time.RFC3339), systemStats[2].String(), systemStats[3].Float(), systemStats[4].Float(), systemStats[5].Float())
-	fmt.Printf("Tasks: %d total, %d running, %d sleeping, %d stopped, %d zombie\n", systemStats[6].Int(), systemStats[7].Int(), systemStats[8].Int(), systemStats[9].Int(), systemStats[10].Int())
-	fmt.Printf("%%Cpu(s): %.2f us, %.2f sy, %.2f ni, %.2f id, %.2f wa, %.2f st\n", systemStats[11].Float(), systemStats[12].Float(), systemStats[13].Float(), systemStats[14].Float(), systemStats[15].Float(), systemStats[16].Float())
-	fmt.Printf("MiB Mem: %.2f total, %.2f free, %.2f used, %.2f buff/cache\n", systemStats[17].Float()/1024, systemStats[18].Float()/1024, (systemStats[17].Float()-systemStats[18].Float())/1024, (systemStats[19].Float()+systemStats[20].Float())/1024)
-	fmt.Printf("MiB Swap: %.2f total, %.2f free, %.2f cached\n", systemStats[21].Float()/1024, systemStats[22].Float()/1024, systemStats[23].Float())
+	for _, networkStat := range current {
+		rate, ok := rateByInterface[networkStat.Interface]
+		if !ok {
+			fmt.Printf("Net %s: %d rx, %d tx (no previous sample, raw counters)\n", networkStat.Interface, networkStat.RxBytes, networkStat.TxBytes)
+			continue
+		}
+		fmt.Printf("Net %s: %s rx, %s tx\n", networkStat.Interface, formatByteRate(rate.RxBytesPerSec), formatByteRate(rate.TxBytesPerSec))
+	}
 }
 
-func printNetworkStats(messageJSON string) {
-	networkStats := gjson.Get(messageJSON, "network").Array()
-	for _, networkStat := range networkStats {
-		fmt.Printf("Net %s: %d rx, %d tx\n", networkStat.Get("interface").String(), networkStat.Get("rx").Int(), networkStat.Get("tx").Int())
+// formatByteRate renders a bytes/second value the way iostat/top render
+// throughput: KB/s below 1 MB/s, MB/s above it.
+func formatByteRate(bytesPerSec float64) string {
+	kbPerSec := bytesPerSec / 1024
+	if kbPerSec >= 1024 {
+		return fmt.Sprintf("%.2f MB/s", kbPerSec/1024)
 	}
+	return fmt.Sprintf("%.2f KB/s", kbPerSec)
 }
 
 func printDiskIOStats(messageJSON string) {
-	diskIOStats := gjson.Get(messageJSON, "diskIO").Array()
-	for _, diskIOStat := range diskIOStats {
-		fmt.Printf("Disk %s: %.2f tps, %.2f rrqm/s, %.2f wrqm/s, %.2f wKB/S, %.2f rKB/S, %.2f avgrq-sz, %.2f avgqu-sz, %.2f await, %.2f %%util\n", diskIOStat.Get("device").String(), diskIOStat.Get("tps").Float(), diskIOStat.Get("rrqmPS").Float(), diskIOStat.Get("wrqmPS").Float(), diskIOStat.Get("writeKbPS").Float(), diskIOStat.Get("readKbPS").Float(),
-			diskIOStat.Get("avgReqSz").Float(), diskIOStat.Get("avgQueueLen").Float(), diskIOStat.Get("await").Float(), diskIOStat.Get("util").Float())
+	for _, diskIOStat := range parseDiskIOStats(messageJSON) {
+		fmt.Printf("Disk %s: %.2f tps, %.2f rrqm/s, %.2f wrqm/s, %.2f wKB/S, %.2f rKB/S, %.2f avgrq-sz, %.2f avgqu-sz, %.2f await, %.2f %%util\n", diskIOStat.Device, diskIOStat.TPS, diskIOStat.ReadRequestMergesPS, diskIOStat.WriteRequestMergesPS, diskIOStat.WriteKBPS, diskIOStat.ReadKBPS,
+			diskIOStat.AvgReqSz, diskIOStat.AvgQueueLen, diskIOStat.Await, diskIOStat.UtilPc)
 	}
 }
 
-func printProcessList(messageJSON string, sortByMem bool) {
-	processes := gjson.Get(messageJSON, "processList").Array()
+func printProcessList(messageJSON string, sortByMem bool, processFilter string) {
 	var processList []Process
-
-	for _, process := range processes {
-		var p Process
-		err := json.Unmarshal([]byte(process.Raw), &p)
-		if err != nil {
-			fmt.Println("Error unmarshaling JSON:", err)
+	for _, p := range parseProcessList(messageJSON) {
+		if processFilter != "" && !strings.Contains(p.Name, processFilter) {
 			continue
 		}
 		processList = append(processList, p)
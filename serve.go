@@ -0,0 +1,239 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// ServeOptions contains command-line options for the `rds-top serve` subcommand.
+type ServeOptions struct {
+	listen       string
+	instanceIDs  []string
+	pollInterval time.Duration
+}
+
+// exporterMetrics are the Prometheus collectors published by `rds-top serve`,
+// built on the same SystemStats/NetworkStat/DiskIOStat/Process models the CLI
+// printer uses so the two presentations can never drift apart.
+type exporterMetrics struct {
+	cpuUtilization *prometheus.GaugeVec
+	loadAverage    *prometheus.GaugeVec
+	memoryKB       *prometheus.GaugeVec
+	swapKB         *prometheus.GaugeVec
+	networkRx      *prometheus.GaugeVec
+	networkTx      *prometheus.GaugeVec
+	diskTPS        *prometheus.GaugeVec
+	diskUtil       *prometheus.GaugeVec
+	diskAwait      *prometheus.GaugeVec
+	processCPU     *prometheus.GaugeVec
+	processRSS     *prometheus.GaugeVec
+
+	// processLabelsMu guards processLabels, which tracks the (pid, name)
+	// label pairs last published for each instance so update() can delete
+	// the ones that no longer appear in the current sample. Without this,
+	// processCPU/processRSS - keyed by pid - would accumulate a stale,
+	// ever-growing series for every PID that has ever existed, since RDS
+	// process PIDs churn constantly.
+	processLabelsMu sync.Mutex
+	processLabels   map[string]map[processLabel]bool
+}
+
+// processLabel is the (pid, name) label pair a process sample is published
+// under.
+type processLabel struct {
+	pid  string
+	name string
+}
+
+func newExporterMetrics() *exporterMetrics {
+	m := &exporterMetrics{
+		cpuUtilization: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "rds_cpu_utilization_percent",
+			Help: "RDS Enhanced Monitoring cpuUtilization.* as a percentage.",
+		}, []string{"instance", "mode"}),
+		loadAverage: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "rds_load_average",
+			Help: "RDS Enhanced Monitoring loadAverageMinute.*.",
+		}, []string{"instance", "period"}),
+		memoryKB: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "rds_memory_kilobytes",
+			Help: "RDS Enhanced Monitoring memory.* in kilobytes.",
+		}, []string{"instance", "field"}),
+		swapKB: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "rds_swap_kilobytes",
+			Help: "RDS Enhanced Monitoring swap.* in kilobytes.",
+		}, []string{"instance", "field"}),
+		networkRx: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "rds_network_receive_bytes",
+			Help: "RDS Enhanced Monitoring network[].rx, by interface.",
+		}, []string{"instance", "interface"}),
+		networkTx: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "rds_network_transmit_bytes",
+			Help: "RDS Enhanced Monitoring network[].tx, by interface.",
+		}, []string{"instance", "interface"}),
+		diskTPS: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "rds_disk_tps",
+			Help: "RDS Enhanced Monitoring diskIO[].tps, by device.",
+		}, []string{"instance", "device"}),
+		diskUtil: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "rds_disk_util_percent",
+			Help: "RDS Enhanced Monitoring diskIO[].util, by device.",
+		}, []string{"instance", "device"}),
+		diskAwait: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "rds_disk_await_milliseconds",
+			Help: "RDS Enhanced Monitoring diskIO[].await, by device.",
+		}, []string{"instance", "device"}),
+		processCPU: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "rds_process_cpu_percent",
+			Help: "RDS Enhanced Monitoring processList[].cpuUsedPc, by pid/name.",
+		}, []string{"instance", "pid", "name"}),
+		processRSS: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "rds_process_rss_kilobytes",
+			Help: "RDS Enhanced Monitoring processList[].rss, by pid/name.",
+		}, []string{"instance", "pid", "name"}),
+		processLabels: make(map[string]map[processLabel]bool),
+	}
+
+	prometheus.MustRegister(m.cpuUtilization, m.loadAverage, m.memoryKB, m.swapKB, m.networkRx, m.networkTx, m.diskTPS, m.diskUtil, m.diskAwait, m.processCPU, m.processRSS)
+	return m
+}
+
+// update publishes one RDSOSMetrics sample for instanceID.
+func (m *exporterMetrics) update(instanceID, messageJSON string) error {
+	stats, err := parseSystemStats(messageJSON)
+	if err != nil {
+		return err
+	}
+
+	m.cpuUtilization.WithLabelValues(instanceID, "user").Set(stats.CPUUser)
+	m.cpuUtilization.WithLabelValues(instanceID, "system").Set(stats.CPUSystem)
+	m.cpuUtilization.WithLabelValues(instanceID, "nice").Set(stats.CPUNice)
+	m.cpuUtilization.WithLabelValues(instanceID, "idle").Set(stats.CPUIdle)
+	m.cpuUtilization.WithLabelValues(instanceID, "wait").Set(stats.CPUWait)
+	m.cpuUtilization.WithLabelValues(instanceID, "steal").Set(stats.CPUSteal)
+
+	m.loadAverage.WithLabelValues(instanceID, "1m").Set(stats.LoadAverage1)
+	m.loadAverage.WithLabelValues(instanceID, "5m").Set(stats.LoadAverage5)
+	m.loadAverage.WithLabelValues(instanceID, "15m").Set(stats.LoadAverage15)
+
+	m.memoryKB.WithLabelValues(instanceID, "total").Set(stats.MemoryTotalKB)
+	m.memoryKB.WithLabelValues(instanceID, "free").Set(stats.MemoryFreeKB)
+	m.memoryKB.WithLabelValues(instanceID, "cached").Set(stats.MemoryCachedKB)
+	m.memoryKB.WithLabelValues(instanceID, "buffers").Set(stats.MemoryBuffersKB)
+
+	m.swapKB.WithLabelValues(instanceID, "total").Set(stats.SwapTotalKB)
+	m.swapKB.WithLabelValues(instanceID, "free").Set(stats.SwapFreeKB)
+	m.swapKB.WithLabelValues(instanceID, "cached").Set(stats.SwapCachedKB)
+
+	for _, n := range parseNetworkStats(messageJSON) {
+		m.networkRx.WithLabelValues(instanceID, n.Interface).Set(float64(n.RxBytes))
+		m.networkTx.WithLabelValues(instanceID, n.Interface).Set(float64(n.TxBytes))
+	}
+
+	for _, d := range parseDiskIOStats(messageJSON) {
+		m.diskTPS.WithLabelValues(instanceID, d.Device).Set(d.TPS)
+		m.diskUtil.WithLabelValues(instanceID, d.Device).Set(d.UtilPc)
+		m.diskAwait.WithLabelValues(instanceID, d.Device).Set(d.Await)
+	}
+
+	current := make(map[processLabel]bool)
+	for _, p := range parseProcessList(messageJSON) {
+		label := processLabel{pid: fmt.Sprintf("%d", p.ID), name: p.Name}
+		current[label] = true
+		m.processCPU.WithLabelValues(instanceID, label.pid, label.name).Set(p.CPUUsedPc)
+		m.processRSS.WithLabelValues(instanceID, label.pid, label.name).Set(float64(p.RSS))
+	}
+
+	m.processLabelsMu.Lock()
+	for label := range m.processLabels[instanceID] {
+		if !current[label] {
+			m.processCPU.DeleteLabelValues(instanceID, label.pid, label.name)
+			m.processRSS.DeleteLabelValues(instanceID, label.pid, label.name)
+		}
+	}
+	m.processLabels[instanceID] = current
+	m.processLabelsMu.Unlock()
+
+	return nil
+}
+
+// runServe implements `rds-top serve`: it polls RDSOSMetrics for each
+// configured instance on a schedule and exposes the parsed fields as
+// Prometheus metrics on --listen, so a Grafana dashboard can be built
+// without going through CloudWatch metric-math costs.
+func runServe(args []string) error {
+	options, err := parseServeFlags(args)
+	if err != nil {
+		return err
+	}
+
+	sess, err := session.NewSessionWithOptions(session.Options{SharedConfigState: session.SharedConfigEnable})
+	if err != nil {
+		return fmt.Errorf("creating AWS session: %w", err)
+	}
+
+	rdsSvc := rds.New(sess)
+	cloudWatchLogsSvc := cloudwatchlogs.New(sess)
+	metrics := newExporterMetrics()
+
+	for _, instanceID := range options.instanceIDs {
+		resourceID, err := getResourceID(instanceID, rdsSvc)
+		if err != nil {
+			return fmt.Errorf("getting resource ID for %s: %w", instanceID, err)
+		}
+		go pollInstance(instanceID, resourceID, options.pollInterval, cloudWatchLogsSvc, metrics)
+	}
+
+	http.Handle("/metrics", promhttp.Handler())
+	log.Printf("rds-top exporter listening on %s for instances: %s", options.listen, strings.Join(options.instanceIDs, ", "))
+	return http.ListenAndServe(options.listen, nil)
+}
+
+// pollInstance fetches the latest sample for one instance every interval and
+// feeds it into metrics, for as long as the process runs.
+func pollInstance(instanceID, resourceID string, interval time.Duration, cloudWatchLogsSvc *cloudwatchlogs.CloudWatchLogs, metrics *exporterMetrics) {
+	for {
+		params := buildLogsParameters(resourceID, 0)
+		messageJSON, err := getLogEvents(params, cloudWatchLogsSvc)
+		if err != nil {
+			log.Printf("Error getting log events for %s: %v", instanceID, err)
+		} else if err := metrics.update(instanceID, messageJSON); err != nil {
+			log.Printf("Error parsing sample for %s: %v", instanceID, err)
+		}
+
+		time.Sleep(interval)
+	}
+}
+
+func parseServeFlags(args []string) (ServeOptions, error) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	listenFlag := fs.String("listen", ":9090", "Address to serve Prometheus metrics on")
+	instancesFlag := fs.String("instances", "", "Required: comma-separated list of RDS instance identifiers to poll")
+	pollIntervalFlag := fs.Int("poll-interval", 60, "Seconds between polls of RDSOSMetrics per instance")
+
+	if err := fs.Parse(args); err != nil {
+		return ServeOptions{}, err
+	}
+
+	if *instancesFlag == "" {
+		return ServeOptions{}, errors.New("--instances is required")
+	}
+
+	return ServeOptions{
+		listen:       *listenFlag,
+		instanceIDs:  strings.Split(*instancesFlag, ","),
+		pollInterval: time.Duration(*pollIntervalFlag) * time.Second,
+	}, nil
+}
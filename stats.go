@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// SystemStats is the parsed system/load/CPU/memory panel of a single
+// RDSOSMetrics sample, shared by the CLI printer and the Prometheus
+// exporter so both read the same fields the same way.
+type SystemStats struct {
+	InstanceID string
+	Timestamp  time.Time
+	Uptime     string
+
+	LoadAverage1  float64
+	LoadAverage5  float64
+	LoadAverage15 float64
+
+	TasksTotal    int64
+	TasksRunning  int64
+	TasksSleeping int64
+	TasksStopped  int64
+	TasksZombie   int64
+
+	CPUUser   float64
+	CPUSystem float64
+	CPUNice   float64
+	CPUIdle   float64
+	CPUWait   float64
+	CPUSteal  float64
+
+	MemoryTotalKB   float64
+	MemoryFreeKB    float64
+	MemoryCachedKB  float64
+	MemoryBuffersKB float64
+
+	SwapTotalKB  float64
+	SwapFreeKB   float64
+	SwapCachedKB float64
+}
+
+// NetworkStat is a single network interface's counters from one sample.
+type NetworkStat struct {
+	Interface string
+	RxBytes   int64
+	TxBytes   int64
+}
+
+// DiskIOStat is a single device's I/O counters from one sample. Unlike
+// NetworkStat's rx/tx, these fields (note the "PS" suffix: tps, writeKbPS,
+// readKbPS, ...) are already per-second rates as reported by RDS Enhanced
+// Monitoring, not cumulative counters, so there is no delta to compute here
+// the way there is for network bytes - they're printed/encoded as-is.
+type DiskIOStat struct {
+	Device               string
+	TPS                  float64
+	ReadRequestMergesPS  float64
+	WriteRequestMergesPS float64
+	WriteKBPS            float64
+	ReadKBPS             float64
+	AvgReqSz             float64
+	AvgQueueLen          float64
+	Await                float64
+	UtilPc               float64
+}
+
+// parseSystemStats extracts the system panel fields from messageJSON.
+func parseSystemStats(messageJSON string) (SystemStats, error) {
+	fields := gjson.GetMany(messageJSON, "instanceID", "timestamp", "uptime", "loadAverageMinute.one", "loadAverageMinute.five", "loadAverageMinute.fifteen", "tasks.total", "tasks.running", "tasks.sleeping", "tasks.stopped", "tasks.zombie", "cpuUtilization.user", "cpuUtilization.system", "cpuUtilization.nice", "cpuUtilization.idle", "cpuUtilization.wait", "cpuUtilization.steal", "memory.total", "memory.free", "memory.cached", "memory.buffers", "swap.total", "swap.free", "swap.cached")
+
+	timestamp, err := time.Parse(time.RFC3339, fields[1].String())
+	if err != nil {
+		return SystemStats{}, fmt.Errorf("parsing timestamp: %w", err)
+	}
+
+	return SystemStats{
+		InstanceID:      fields[0].String(),
+		Timestamp:       timestamp,
+		Uptime:          fields[2].String(),
+		LoadAverage1:    fields[3].Float(),
+		LoadAverage5:    fields[4].Float(),
+		LoadAverage15:   fields[5].Float(),
+		TasksTotal:      fields[6].Int(),
+		TasksRunning:    fields[7].Int(),
+		TasksSleeping:   fields[8].Int(),
+		TasksStopped:    fields[9].Int(),
+		TasksZombie:     fields[10].Int(),
+		CPUUser:         fields[11].Float(),
+		CPUSystem:       fields[12].Float(),
+		CPUNice:         fields[13].Float(),
+		CPUIdle:         fields[14].Float(),
+		CPUWait:         fields[15].Float(),
+		CPUSteal:        fields[16].Float(),
+		MemoryTotalKB:   fields[17].Float(),
+		MemoryFreeKB:    fields[18].Float(),
+		MemoryCachedKB:  fields[19].Float(),
+		MemoryBuffersKB: fields[20].Float(),
+		SwapTotalKB:     fields[21].Float(),
+		SwapFreeKB:      fields[22].Float(),
+		SwapCachedKB:    fields[23].Float(),
+	}, nil
+}
+
+// NetworkRate is a per-interface rx/tx rate, derived by diffing two
+// consecutive NetworkStat samples over the elapsed time between them.
+type NetworkRate struct {
+	Interface     string
+	RxBytesPerSec float64
+	TxBytesPerSec float64
+}
+
+// computeNetworkRates diffs current against previous (matched by interface
+// name) over the elapsed time between the two samples. Interfaces absent
+// from previous (e.g. the very first sample, or a hot-attached interface)
+// are skipped rather than reported as a rate from zero.
+func computeNetworkRates(previous cachedSample, currentTimestamp time.Time, current []NetworkStat) []NetworkRate {
+	elapsed := currentTimestamp.Sub(previous.Timestamp).Seconds()
+	if elapsed <= 0 {
+		return nil
+	}
+
+	previousByInterface := make(map[string]NetworkStat, len(previous.Network))
+	for _, n := range previous.Network {
+		previousByInterface[n.Interface] = n
+	}
+
+	rates := make([]NetworkRate, 0, len(current))
+	for _, n := range current {
+		prev, ok := previousByInterface[n.Interface]
+		if !ok {
+			continue
+		}
+		rates = append(rates, NetworkRate{
+			Interface:     n.Interface,
+			RxBytesPerSec: float64(n.RxBytes-prev.RxBytes) / elapsed,
+			TxBytesPerSec: float64(n.TxBytes-prev.TxBytes) / elapsed,
+		})
+	}
+	return rates
+}
+
+// parseNetworkStats extracts the per-interface network panel from messageJSON.
+func parseNetworkStats(messageJSON string) []NetworkStat {
+	raw := gjson.Get(messageJSON, "network").Array()
+	stats := make([]NetworkStat, 0, len(raw))
+	for _, r := range raw {
+		stats = append(stats, NetworkStat{
+			Interface: r.Get("interface").String(),
+			RxBytes:   r.Get("rx").Int(),
+			TxBytes:   r.Get("tx").Int(),
+		})
+	}
+	return stats
+}
+
+// parseDiskIOStats extracts the per-device disk I/O panel from messageJSON.
+func parseDiskIOStats(messageJSON string) []DiskIOStat {
+	raw := gjson.Get(messageJSON, "diskIO").Array()
+	stats := make([]DiskIOStat, 0, len(raw))
+	for _, r := range raw {
+		stats = append(stats, DiskIOStat{
+			Device:               r.Get("device").String(),
+			TPS:                  r.Get("tps").Float(),
+			ReadRequestMergesPS:  r.Get("rrqmPS").Float(),
+			WriteRequestMergesPS: r.Get("wrqmPS").Float(),
+			WriteKBPS:            r.Get("writeKbPS").Float(),
+			ReadKBPS:             r.Get("readKbPS").Float(),
+			AvgReqSz:             r.Get("avgReqSz").Float(),
+			AvgQueueLen:          r.Get("avgQueueLen").Float(),
+			Await:                r.Get("await").Float(),
+			UtilPc:               r.Get("util").Float(),
+		})
+	}
+	return stats
+}
+
+// parseProcessList extracts the process table from messageJSON. Entries that
+// fail to unmarshal are skipped with a warning, matching the tolerance the
+// original single-shot printer had for malformed process entries.
+func parseProcessList(messageJSON string) []Process {
+	raw := gjson.Get(messageJSON, "processList").Array()
+	processes := make([]Process, 0, len(raw))
+	for _, r := range raw {
+		var p Process
+		if err := json.Unmarshal([]byte(r.Raw), &p); err != nil {
+			fmt.Println("Error unmarshaling JSON:", err)
+			continue
+		}
+		processes = append(processes, p)
+	}
+	return processes
+}